@@ -0,0 +1,479 @@
+package main
+
+// alerts.go 实现了一个仿 Prometheus Alertmanager 的告警流水线。
+//
+// 探测结果不再像旧的 monitorOnce 那样直接拼装飞书卡片：probeTarget 把每次探测的结果封装成 Alert
+// 写入 Dispatcher 的 channel；Dispatcher 负责按 labels 分组、应用
+// group_wait/group_interval/repeat_interval 定时器、过滤静默期内的
+// 告警、应用抑制规则，最后把分组后的通知交给匹配到的 Notifier 发送。
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Alert 是一条告警事件，Labels 用于分组/匹配，Annotations 携带人类可读的详情。
+// EndsAt 非零表示该告警已恢复（用于清除分组、解除抑制）。
+type Alert struct {
+	Labels      map[string]string
+	Annotations map[string]string
+	StartsAt    time.Time
+	EndsAt      time.Time
+}
+
+func (a Alert) resolved() bool {
+	return !a.EndsAt.IsZero() && !a.EndsAt.After(time.Now())
+}
+
+func (a Alert) fingerprint() string {
+	return labelsKey(a.Labels, nil)
+}
+
+// identity 是 Alert 在 firing/resolved 两种状态下保持不变的身份标识，用于
+// d.active 的存取：必须排除 "status"，否则一条告警 firing 时和它自己的
+// resolve 事件会算出不同的 key，resolve 永远删不掉 firing 时留下的记录。
+func (a Alert) identity() string {
+	labels := make(map[string]string, len(a.Labels))
+	for k, v := range a.Labels {
+		if k == "status" {
+			continue
+		}
+		labels[k] = v
+	}
+	return labelsKey(labels, nil)
+}
+
+// AlertGroup 是按 group_by labels 聚合出的一批告警，对应一次通知。
+type AlertGroup struct {
+	Labels     map[string]string
+	Alerts     []Alert
+	firstSeen  time.Time
+	lastSent   time.Time
+	notified   map[string]bool // 已经通知过的 fingerprint，用来区分本轮是新增告警还是同一批告警的重复提醒
+	timerArmed bool            // 是否已经有一个 flush 定时器在等待触发；上次 flush 发现没有待发告警时会清零，等下一条告警到来时重新武装
+}
+
+// Matcher 描述一个 label 匹配条件，Regex 为 true 时 Value 按正则匹配。
+type Matcher struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+	Regex bool   `yaml:"regex"`
+}
+
+func (m Matcher) matches(labels map[string]string) bool {
+	v, ok := labels[m.Name]
+	if !ok {
+		return false
+	}
+	if m.Regex {
+		re, err := regexp.Compile(m.Value)
+		if err != nil {
+			return false
+		}
+		return re.MatchString(v)
+	}
+	return v == m.Value
+}
+
+func matchersFromMap(m map[string]string) []Matcher {
+	matchers := make([]Matcher, 0, len(m))
+	for k, v := range m {
+		matchers = append(matchers, Matcher{Name: k, Value: v})
+	}
+	return matchers
+}
+
+func matchesAll(matchers []Matcher, labels map[string]string) bool {
+	for _, m := range matchers {
+		if !m.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// Silence 在 [StartsAt, EndsAt) 区间内屏蔽匹配到的告警。
+type Silence struct {
+	ID        string    `json:"id"`
+	Matchers  []Matcher `json:"matchers"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	CreatedBy string    `json:"created_by"`
+	Comment   string    `json:"comment"`
+}
+
+func (s Silence) active(now time.Time) bool {
+	return !now.Before(s.StartsAt) && now.Before(s.EndsAt)
+}
+
+// SilenceStore 把静默规则持久化到一个小 JSON 文件，每次 Add 后整体覆写。
+// 数据量级（运维手工创建的静默）很小，没必要引入 bolt 之类的嵌入式数据库。
+type SilenceStore struct {
+	mu       sync.Mutex
+	path     string
+	silences []Silence
+}
+
+func NewSilenceStore(path string) (*SilenceStore, error) {
+	s := &SilenceStore{path: path}
+	if path == "" {
+		return s, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if len(data) == 0 {
+		return s, nil
+	}
+	if err := json.Unmarshal(data, &s.silences); err != nil {
+		return nil, fmt.Errorf("解析静默文件 %s 失败: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *SilenceStore) IsSilenced(labels map[string]string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, sil := range s.silences {
+		if sil.active(now) && matchesAll(sil.Matchers, labels) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *SilenceStore) Add(sil Silence) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.silences = append(s.silences, sil)
+	return s.saveLocked()
+}
+
+// List 返回当前持有的静默规则快照，SilencesHandler 用它实现 GET /-/silences。
+func (s *SilenceStore) List() []Silence {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Silence, len(s.silences))
+	copy(out, s.silences)
+	return out
+}
+
+func (s *SilenceStore) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.silences, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// InhibitRule 在 SourceMatch 匹配到的告警处于 firing 状态时，抑制所有
+// 同时匹配 TargetMatch 且 Equal 列出的 label 值相同的告警。
+type InhibitRule struct {
+	SourceMatch map[string]string `yaml:"source_match"`
+	TargetMatch map[string]string `yaml:"target_match"`
+	Equal       []string          `yaml:"equal"`
+}
+
+// Notifier 是告警通知的发送端点，Feishu 卡片、通用 webhook、stdout 都实现它。
+type Notifier interface {
+	Name() string
+	Notify(group *AlertGroup) error
+}
+
+type StdoutNotifier struct{}
+
+func (StdoutNotifier) Name() string { return "stdout" }
+
+func (StdoutNotifier) Notify(group *AlertGroup) error {
+	for _, a := range group.Alerts {
+		fmt.Printf("[ALERT] %s - %s\n", a.Labels["url"], a.Annotations["detail"])
+	}
+	return nil
+}
+
+// Route 把匹配到 Match 的告警分组路由到 Receiver 指定的 Notifier。
+type Route struct {
+	Match    map[string]string `yaml:"match"`
+	Receiver string            `yaml:"receiver"`
+}
+
+// AlertingConfig 对应 config.yaml 里的 alerting: 小节，结构上模仿
+// Alertmanager 的 route/receiver/inhibit_rules 三段式配置。
+type AlertingConfig struct {
+	GroupBy           []string         `yaml:"group_by"`
+	GroupWaitSec      int              `yaml:"group_wait_seconds"`
+	GroupIntervalSec  int              `yaml:"group_interval_seconds"`
+	RepeatIntervalSec int              `yaml:"repeat_interval_seconds"`
+	Routes            []Route          `yaml:"routes"`
+	Receivers         []ReceiverConfig `yaml:"receivers"`
+	InhibitRules      []InhibitRule    `yaml:"inhibit_rules"`
+	SilencesFile      string           `yaml:"silences_file"`
+}
+
+type ReceiverConfig struct {
+	Name          string `yaml:"name"`
+	FeishuWebhook string `yaml:"feishu_webhook"`
+	WebhookURL    string `yaml:"webhook_url"`
+	Stdout        bool   `yaml:"stdout"`
+}
+
+func (c AlertingConfig) groupWait() time.Duration {
+	if c.GroupWaitSec <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(c.GroupWaitSec) * time.Second
+}
+
+func (c AlertingConfig) groupInterval() time.Duration {
+	if c.GroupIntervalSec <= 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(c.GroupIntervalSec) * time.Second
+}
+
+func (c AlertingConfig) repeatInterval() time.Duration {
+	if c.RepeatIntervalSec <= 0 {
+		return 4 * time.Hour
+	}
+	return time.Duration(c.RepeatIntervalSec) * time.Second
+}
+
+// Dispatcher 从 events channel 消费 Alert，完成分组、静默、抑制和通知分发。
+type Dispatcher struct {
+	cfg       AlertingConfig
+	events    chan Alert
+	notifiers map[string]Notifier
+	silences  *SilenceStore
+
+	mu     sync.Mutex
+	groups map[string]*AlertGroup
+	active map[string]Alert
+}
+
+func NewDispatcher(cfg AlertingConfig, notifiers map[string]Notifier, silences *SilenceStore) *Dispatcher {
+	return &Dispatcher{
+		cfg:       cfg,
+		events:    make(chan Alert, 256),
+		notifiers: notifiers,
+		silences:  silences,
+		groups:    make(map[string]*AlertGroup),
+		active:    make(map[string]Alert),
+	}
+}
+
+// Send 把一个 Alert 事件交给 Dispatcher，probeTarget 在每次探测后调用。
+func (d *Dispatcher) Send(a Alert) {
+	d.events <- a
+}
+
+// Silences 返回 Dispatcher 使用的 SilenceStore（没有配置 silences_file 时为
+// nil），SilencesHandler 用它来实现运行时创建静默。
+func (d *Dispatcher) Silences() *SilenceStore {
+	return d.silences
+}
+
+func (d *Dispatcher) Run(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case a := <-d.events:
+			d.handle(a)
+		}
+	}
+}
+
+func (d *Dispatcher) handle(a Alert) {
+	d.mu.Lock()
+	if a.resolved() {
+		delete(d.active, a.identity())
+	} else {
+		d.active[a.identity()] = a
+	}
+	d.mu.Unlock()
+
+	if a.resolved() {
+		return
+	}
+	if d.silences != nil && d.silences.IsSilenced(a.Labels) {
+		log.Printf("告警 %s 命中静默规则，已屏蔽\n", a.Labels["url"])
+		return
+	}
+	if d.isInhibited(a) {
+		log.Printf("告警 %s 被抑制规则屏蔽\n", a.Labels["url"])
+		return
+	}
+
+	key := labelsKey(a.Labels, d.cfg.GroupBy)
+	d.mu.Lock()
+	g, ok := d.groups[key]
+	if !ok {
+		g = &AlertGroup{Labels: projectLabels(a.Labels, d.cfg.GroupBy), firstSeen: time.Now(), notified: make(map[string]bool)}
+		d.groups[key] = g
+	}
+	g.Alerts = append(g.Alerts, a)
+	// 上一轮 flush 如果发现分组里没有待发告警，会把 timerArmed 清零并且不再
+	// 重新安排定时器；这里只要分组处于这种“休眠”状态就需要重新武装，不能只看
+	// 分组是否已经在 d.groups 里存在，否则一轮静默期之后的新告警永远不会被
+	// flush 拾取。
+	shouldFlushNow := !g.timerArmed
+	g.timerArmed = true
+	d.mu.Unlock()
+
+	if shouldFlushNow {
+		time.AfterFunc(d.cfg.groupWait(), func() { d.flush(key) })
+	}
+}
+
+// isInhibited 判断 a 是否被某条当前 firing 中的告警按 InhibitRule 抑制。
+func (d *Dispatcher) isInhibited(a Alert) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, rule := range d.cfg.InhibitRules {
+		if !matchesAll(matchersFromMap(rule.TargetMatch), a.Labels) {
+			continue
+		}
+		for _, source := range d.active {
+			if !matchesAll(matchersFromMap(rule.SourceMatch), source.Labels) {
+				continue
+			}
+			if equalOnLabels(source.Labels, a.Labels, rule.Equal) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func equalOnLabels(a, b map[string]string, names []string) bool {
+	for _, name := range names {
+		if a[name] != b[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// flush 把分组到期发送一次通知，并按 repeat_interval/group_interval 重新安排下一次 flush。
+func (d *Dispatcher) flush(key string) {
+	d.mu.Lock()
+	g, ok := d.groups[key]
+	if !ok {
+		d.mu.Unlock()
+		return
+	}
+	if len(g.Alerts) == 0 {
+		// 没有待发告警了，分组进入休眠：清掉 armed 标记，下一条新告警到来时
+		// 由 handle() 重新安排 flush，而不是让这个 key 再也没有定时器。
+		g.timerArmed = false
+		d.mu.Unlock()
+		return
+	}
+	pending := g.Alerts
+	g.Alerts = nil
+	g.lastSent = time.Now()
+
+	hasNew := false
+	for _, a := range pending {
+		fp := a.fingerprint()
+		if !g.notified[fp] {
+			hasNew = true
+			g.notified[fp] = true
+		}
+	}
+	d.mu.Unlock()
+
+	batch := &AlertGroup{Labels: g.Labels, Alerts: pending, firstSeen: g.firstSeen, lastSent: g.lastSent}
+	for _, r := range d.matchingReceivers(g.Labels) {
+		n, ok := d.notifiers[r]
+		if !ok {
+			continue
+		}
+		if err := n.Notify(batch); err != nil {
+			log.Printf("通知渠道 %s 发送失败: %v\n", n.Name(), err)
+		}
+	}
+
+	// 本轮有新告警（或新 fingerprint）加入，按 group_interval 再看看是否还有更多
+	// 要合并进来；如果本轮只是同一批告警原样重复，说明它们还在 firing 但没有
+	// 变化，按更长的 repeat_interval 提醒一次即可。
+	next := d.cfg.groupInterval()
+	if !hasNew {
+		next = d.cfg.repeatInterval()
+	}
+	time.AfterFunc(next, func() { d.flush(key) })
+}
+
+func (d *Dispatcher) matchingReceivers(labels map[string]string) []string {
+	for _, route := range d.cfg.Routes {
+		if matchesAll(matchersFromMap(route.Match), labels) {
+			return []string{route.Receiver}
+		}
+	}
+	// 没有命中任何路由规则时，通知所有已配置的 receiver，保证默认情况下不丢通知。
+	names := make([]string, 0, len(d.notifiers))
+	for name := range d.notifiers {
+		names = append(names, name)
+	}
+	return names
+}
+
+func labelsKey(labels map[string]string, groupBy []string) string {
+	keys := groupBy
+	if keys == nil {
+		keys = make([]string, 0, len(labels))
+		for k := range labels {
+			keys = append(keys, k)
+		}
+	}
+	// map 迭代顺序每次都会被重新随机化，即便 groupBy 为 nil 时键集合不变，
+	// 不排序会导致同一 label 集合在不同调用间算出不同的 key。
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	s := ""
+	for _, k := range sorted {
+		s += k + "=" + labels[k] + ","
+	}
+	return s
+}
+
+func projectLabels(labels map[string]string, groupBy []string) map[string]string {
+	if groupBy == nil {
+		return labels
+	}
+	out := make(map[string]string, len(groupBy))
+	for _, k := range groupBy {
+		out[k] = labels[k]
+	}
+	return out
+}
+
+// buildNotifiers 按 receivers 配置实例化 Notifier。
+func buildNotifiers(receivers []ReceiverConfig) map[string]Notifier {
+	notifiers := make(map[string]Notifier, len(receivers))
+	for _, r := range receivers {
+		switch {
+		case r.FeishuWebhook != "":
+			notifiers[r.Name] = &FeishuNotifier{Webhook: r.FeishuWebhook}
+		case r.WebhookURL != "":
+			notifiers[r.Name] = &WebhookNotifier{URL: r.WebhookURL}
+		default:
+			notifiers[r.Name] = StdoutNotifier{}
+		}
+	}
+	return notifiers
+}
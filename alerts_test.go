@@ -0,0 +1,87 @@
+package main
+
+// alerts_test.go 覆盖 Dispatcher 里最容易被破坏的两条路径：同一 label 集合
+// 在一次静默期之后能不能继续触发通知（flush 定时器重新武装），以及告警
+// resolve 之后 d.active 是否真的清空（影响 isInhibited 的长期正确性）。
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingNotifier 只记录收到的 Notify 调用次数，供测试断言使用。
+type countingNotifier struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (n *countingNotifier) Name() string { return "test" }
+
+func (n *countingNotifier) Notify(group *AlertGroup) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls++
+	return nil
+}
+
+func (n *countingNotifier) count() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.calls
+}
+
+// TestDispatcherFiresAfterQuietPeriod 复现了 review 里指出的两个问题：
+//  1. 一轮告警 flush 完、分组的 Alerts 排空之后，同一 label 集合的新告警
+//     必须还能触发下一轮 flush，而不是永远卡在 g.Alerts 里没人处理。
+//  2. resolve 事件必须清掉 d.active 里对应的 firing 记录，否则旧的 ERROR
+//     会永久抑制后续同 url 的告警。
+func TestDispatcherFiresAfterQuietPeriod(t *testing.T) {
+	cfg := AlertingConfig{
+		GroupBy:           []string{"url"},
+		GroupWaitSec:      1,
+		GroupIntervalSec:  1,
+		RepeatIntervalSec: 1,
+	}
+	notifier := &countingNotifier{}
+	d := NewDispatcher(cfg, map[string]Notifier{"default": notifier}, nil)
+
+	stop := make(chan struct{})
+	go d.Run(stop)
+	defer close(stop)
+
+	labels := map[string]string{"url": "example.com", "probe": "http"}
+
+	fire := func(status string, endsAt time.Time) Alert {
+		l := make(map[string]string, len(labels)+1)
+		for k, v := range labels {
+			l[k] = v
+		}
+		l["status"] = status
+		return Alert{Labels: l, StartsAt: time.Now(), EndsAt: endsAt}
+	}
+
+	d.Send(fire("ERROR", time.Time{}))
+	time.Sleep(1500 * time.Millisecond)
+	if got := notifier.count(); got != 1 {
+		t.Fatalf("首次 flush 之后通知次数 = %d，want 1", got)
+	}
+
+	d.Send(fire("OK", time.Now()))
+	time.Sleep(100 * time.Millisecond)
+	d.mu.Lock()
+	activeAfterResolve := len(d.active)
+	d.mu.Unlock()
+	if activeAfterResolve != 0 {
+		t.Fatalf("resolve 之后 d.active 还剩 %d 条，want 0（陈旧的 firing 记录没有被清除）", activeAfterResolve)
+	}
+
+	// 等下一轮 group_interval flush 自然把 Alerts 排空，分组进入休眠。
+	time.Sleep(1500 * time.Millisecond)
+
+	d.Send(fire("ERROR", time.Time{}))
+	time.Sleep(1500 * time.Millisecond)
+	if got := notifier.count(); got != 2 {
+		t.Fatalf("静默期之后再次 firing 的通知次数 = %d，want 2（定时器没有被重新武装）", got)
+	}
+}
@@ -3,14 +3,12 @@ package main
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
-	"sync"
 	"syscall"
 	"time"
 
@@ -30,9 +28,12 @@ import (
 
 type Config struct {
 	Monitor struct {
-		URLs          []string `yaml:"urls"`
-		Interval      int      `yaml:"interval_seconds"`
-		TimeoutSecond int      `yaml:"timeout_seconds"`
+		URLs                []string       `yaml:"urls"`
+		Targets             []TargetConfig `yaml:"targets"`
+		Interval            int            `yaml:"interval_seconds"`
+		TimeoutSecond       int            `yaml:"timeout_seconds"`
+		MaxConcurrentProbes int            `yaml:"max_concurrent_probes"`
+		JitterPercent       int            `yaml:"jitter_percent"`
 	} `yaml:"monitor"`
 	Feishu struct {
 		Webhook string `yaml:"webhook"`
@@ -41,9 +42,11 @@ type Config struct {
 		File string `yaml:"file"`
 	} `yaml:"log"`
 	Alert struct {
-		CooldownSeconds    int `yaml:"cooldown_seconds"`
 		LatencyThresholdMS int `yaml:"latency_threshold_ms"`
 	} `yaml:"alert"`
+	Alerting    AlertingConfig    `yaml:"alerting"`
+	Reload      ReloadConfig      `yaml:"reload"`
+	RemoteWrite RemoteWriteConfig `yaml:"remote_write"`
 }
 
 type FeishuCard struct {
@@ -70,29 +73,94 @@ var (
 )
 
 type AlertPolicy struct {
-	Cooldown         time.Duration
 	LatencyThreshold time.Duration
 }
 
 type MonitorRuntime struct {
-	URLs     []string
-	Webhook  string
-	Interval time.Duration
-	Timeout  time.Duration
-	Policy   AlertPolicy
+	Targets     []TargetConfig
+	Interval    time.Duration
+	Timeout     time.Duration
+	Policy      AlertPolicy
+	Dispatcher  *Dispatcher
+	Scheduler   *Scheduler
+	RemoteWrite *RemoteWriteQueue
 }
 
-var (
-	lastAlertMu sync.Mutex
-	lastAlert   = make(map[string]time.Time)
-)
+// targetsFromURLs 把历史配置里的裸 URL 列表转换成 http 类型的 Target，
+// 让旧的 config.yaml/MONITOR_URLS 不用修改也能在新的 Prober 体系下工作。
+func targetsFromURLs(urls []string) []TargetConfig {
+	targets := make([]TargetConfig, 0, len(urls))
+	for _, u := range urls {
+		targets = append(targets, TargetConfig{Name: u, Type: "http", Address: u})
+	}
+	return targets
+}
+
+// buildRuntime 把一份已经解析好的 Config 转换成可运行的 MonitorRuntime 和
+// 与之配套的 Dispatcher。NewConfigReloader 和 ConfigReloader.Reload 共用这个函数，保证重新加载出来
+// 的 runtime 与启动时的 runtime 构造方式完全一致。
+func buildRuntime(cfg *Config) (*MonitorRuntime, *Dispatcher, error) {
+	targets := append([]TargetConfig{}, cfg.Monitor.Targets...)
+	targets = append(targets, targetsFromURLs(cfg.Monitor.URLs)...)
+	if len(targets) == 0 {
+		return nil, nil, fmt.Errorf("配置中的目标列表为空")
+	}
+
+	policy := AlertPolicy{
+		LatencyThreshold: time.Duration(cfg.Alert.LatencyThresholdMS) * time.Millisecond,
+	}
+
+	alerting := cfg.Alerting
+	// 没有显式配置 alerting.receivers 时，沿用旧的单一飞书 webhook 作为默认 receiver，
+	// 这样历史配置文件不用改也能继续收到告警。
+	if len(alerting.Receivers) == 0 && cfg.Feishu.Webhook != "" {
+		alerting.Receivers = []ReceiverConfig{{Name: "default", FeishuWebhook: cfg.Feishu.Webhook}}
+	}
+
+	var silences *SilenceStore
+	if alerting.SilencesFile != "" {
+		store, err := NewSilenceStore(alerting.SilencesFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("加载静默文件失败: %w", err)
+		}
+		silences = store
+	}
+	dispatcher := NewDispatcher(alerting, buildNotifiers(alerting.Receivers), silences)
+
+	interval := time.Duration(cfg.Monitor.Interval) * time.Second
+	timeout := time.Duration(cfg.Monitor.TimeoutSecond) * time.Second
+
+	var remoteWrite *RemoteWriteQueue
+	if cfg.RemoteWrite.enabled() {
+		remoteWrite = NewRemoteWriteQueue(cfg.RemoteWrite)
+	}
+
+	scheduler := NewScheduler(cfg.Monitor.MaxConcurrentProbes, cfg.Monitor.JitterPercent)
+	for _, target := range targets {
+		if err := scheduler.Schedule(target, interval, timeout, dispatcher, policy, remoteWrite); err != nil {
+			return nil, nil, fmt.Errorf("调度目标失败: %w", err)
+		}
+	}
+
+	runtime := &MonitorRuntime{
+		Targets:     targets,
+		Interval:    interval,
+		Timeout:     timeout,
+		Policy:      policy,
+		Dispatcher:  dispatcher,
+		Scheduler:   scheduler,
+		RemoteWrite: remoteWrite,
+	}
+	return runtime, dispatcher, nil
+}
 
 func init() {
 	prometheus.MustRegister(reqTotal, reqDuration)
 }
 
-func loadConfig() (*Config, error) {
-	data, err := os.ReadFile("config.yaml")
+// loadConfigFrom 解析指定路径的配置文件，ConfigReloader.Reload 复用它来做热加载时的重新解析。
+func loadConfigFrom(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		// 没有配置文件则返回 nil，由上层走环境变量逻辑
 		return nil, err
@@ -110,9 +178,6 @@ func loadConfig() (*Config, error) {
 	if cfg.Log.File == "" {
 		cfg.Log.File = "monitor.log"
 	}
-	if cfg.Alert.CooldownSeconds < 0 {
-		cfg.Alert.CooldownSeconds = 0
-	}
 	if cfg.Alert.LatencyThresholdMS < 0 {
 		cfg.Alert.LatencyThresholdMS = 0
 	}
@@ -139,145 +204,13 @@ func getEnv(key, def string) string {
 	return def
 }
 
-func monitorOnce(urls []string, timeout time.Duration, webhook string, policy AlertPolicy) {
-	client := &http.Client{
-		Timeout: timeout,
-	}
-
-	for _, u := range urls {
-		start := time.Now()
-		resp, err := client.Get(u)
-		latency := time.Since(start)
-
-		status := "OK"
-		var detail string
-
-		if err != nil {
-			status = "ERROR"
-			detail = err.Error()
-		} else {
-			defer resp.Body.Close()
-			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-				status = "ERROR"
-				detail = fmt.Sprintf("HTTP 状态码: %d", resp.StatusCode)
-			} else {
-				detail = fmt.Sprintf("HTTP %d, 耗时 %v", resp.StatusCode, latency)
-			}
-		}
-
-		alertNeeded := false
-		alertReason := detail
-
-		if policy.LatencyThreshold > 0 && status == "OK" && latency > policy.LatencyThreshold {
-			status = "SLOW"
-			alertNeeded = true
-			alertReason = fmt.Sprintf("响应耗时 %v 超过阈值 %v", latency, policy.LatencyThreshold)
-		}
-
-		reqTotal.WithLabelValues(u, status).Inc()
-		reqDuration.WithLabelValues(u).Observe(latency.Seconds())
-
-		if status == "ERROR" {
-			alertNeeded = true
-		}
-
-		if alertNeeded {
-			log.Printf("[ALERT] %s - %s (reason: %s)\n", u, detail, alertReason)
-			fmt.Printf("[ALERT] %s - %s (reason: %s)\n", u, detail, alertReason)
-
-			if webhook != "" && canSendAlert(u, policy.Cooldown) {
-				if err := sendFeishuCard(webhook, u, status, alertReason, latency); err != nil {
-					log.Printf("发送飞书告警失败: %v\n", err)
-				} else {
-					recordAlert(u)
-				}
-			}
-		} else {
-			log.Printf("[OK] %s - %s\n", u, detail)
-			fmt.Printf("[OK] %s - %s\n", u, detail)
-			resetAlert(u)
-		}
-	}
-}
-
-func canSendAlert(url string, cooldown time.Duration) bool {
-	if cooldown <= 0 {
-		return true
-	}
-	lastAlertMu.Lock()
-	defer lastAlertMu.Unlock()
-
-	last, ok := lastAlert[url]
-	if !ok || last.IsZero() {
-		return true
-	}
-	return time.Since(last) >= cooldown
-}
-
-func recordAlert(url string) {
-	lastAlertMu.Lock()
-	defer lastAlertMu.Unlock()
-	lastAlert[url] = time.Now()
-}
-
-func resetAlert(url string) {
-	lastAlertMu.Lock()
-	defer lastAlertMu.Unlock()
-	delete(lastAlert, url)
-}
-
-func sendFeishuCard(webhook, url, status, detail string, latency time.Duration) error {
-	card := map[string]interface{}{
-		"config": map[string]interface{}{
-			"wide_screen_mode": true,
-		},
-		"header": map[string]interface{}{
-			"title": map[string]string{
-				"tag":     "plain_text",
-				"content": "HTTP 监控告警",
-			},
-			"template": "red",
-		},
-		"elements": []interface{}{
-			map[string]interface{}{
-				"tag": "div",
-				"text": map[string]string{
-					"tag": "lark_md",
-					"content": fmt.Sprintf(
-						"**URL**: %s\n**状态**: %s\n**详情**: %s\n**耗时**: %v",
-						url, status, detail, latency,
-					),
-				},
-			},
-		},
-	}
-
-	body := FeishuCard{
-		MsgType: "interactive",
-		Card:    card,
-	}
-
-	data, err := json.Marshal(body)
-	if err != nil {
-		return err
-	}
-
-	resp, err := http.Post(webhook, "application/json", bytes.NewReader(data))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("feishu 返回非 200 状态码: %d", resp.StatusCode)
-	}
-
-	return nil
-}
-
-func startMetricsServer(ctx context.Context, addr string) {
+func startMetricsServer(ctx context.Context, addr string, reloader *ConfigReloader) {
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
+	if reloader != nil {
+		mux.HandleFunc("/-/reload", reloader.ReloadHandler())
+		mux.HandleFunc("/-/silences", reloader.SilencesHandler())
+	}
 	server := &http.Server{
 		Addr:    addr,
 		Handler: mux,
@@ -300,120 +233,70 @@ func startMetricsServer(ctx context.Context, addr string) {
 	}()
 }
 
-func runMonitorLoop(ctx context.Context, runtime MonitorRuntime) {
-	ticker := time.NewTicker(runtime.Interval)
-	defer ticker.Stop()
-
-	log.Printf("monitor started with %d urls, interval=%s, timeout=%s, cooldown=%s, latency_threshold=%s\n",
-		len(runtime.URLs), runtime.Interval, runtime.Timeout, runtime.Policy.Cooldown, runtime.Policy.LatencyThreshold)
-	fmt.Printf("开始监控 %d 个 URL，每 %s 检查一次\n", len(runtime.URLs), runtime.Interval)
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("monitor loop exiting")
-			return
-		default:
-			monitorOnce(runtime.URLs, runtime.Timeout, runtime.Webhook, runtime.Policy)
-		}
+// runMonitorLoop 本身不再驱动探测：每个目标的探测节奏由 reloader 当前持有的
+// MonitorRuntime.Scheduler（cron 调度 + worker 池）负责，ConfigReloader.Reload 热替换
+// runtime 时会原子地启停新旧 Scheduler。这里只是阻塞到 ctx 被取消为止。
+func runMonitorLoop(ctx context.Context, reloader *ConfigReloader) {
+	initial := reloader.Current()
+	log.Printf("monitor started with %d targets\n", len(initial.Targets))
+	fmt.Printf("开始监控 %d 个目标\n", len(initial.Targets))
 
-		select {
-		case <-ctx.Done():
-			log.Println("monitor loop exiting")
-			return
-		case <-ticker.C:
-		}
-	}
+	<-ctx.Done()
+	log.Println("monitor loop exiting")
 }
 
 func main() {
 	flag.Parse()
 
-	// 优先尝试从 config.yaml 加载
-	cfg, err := loadConfig()
-	var urls []string
-	var webhook string
-	var intervalSec int
-	var timeout time.Duration
-	var policy AlertPolicy
-
-	if err == nil && cfg != nil {
-		urls = cfg.Monitor.URLs
-		webhook = cfg.Feishu.Webhook
-		intervalSec = cfg.Monitor.Interval
-		timeout = time.Duration(cfg.Monitor.TimeoutSecond) * time.Second
-		setupLogger(cfg.Log.File)
-		policy = AlertPolicy{
-			Cooldown:         time.Duration(cfg.Alert.CooldownSeconds) * time.Second,
-			LatencyThreshold: time.Duration(cfg.Alert.LatencyThresholdMS) * time.Millisecond,
-		}
-		fmt.Println("已从 config.yaml 加载配置")
-	} else {
-		// 回退到环境变量
-		rawURLs := getEnv("MONITOR_URLS", "")
-		webhook = getEnv("FEISHU_WEBHOOK", "")
-		intervalStr := getEnv("INTERVAL_SECONDS", "10")
-		logFile := getEnv("LOG_FILE", "monitor.log")
-		setupLogger(logFile)
-
-		if rawURLs == "" {
-			fmt.Println("请通过 config.yaml 或环境变量 MONITOR_URLS 设置要监控的 URL，多个用逗号分隔")
-			os.Exit(1)
-		}
+	// 优先尝试从 --config 指定的文件加载（默认 config.yaml）；能从文件加载
+	// 成功时，该文件之后还会被 ConfigReloader 监听并热加载，所以这里记下它的路径。
+	configPath := *serviceConfigPath
+	cfg, err := loadConfigFrom(configPath)
+	reloadable := err == nil && cfg != nil
 
-		fmt.Sscanf(intervalStr, "%d", &intervalSec)
-		if intervalSec <= 0 {
-			intervalSec = 10
+	if reloadable {
+		if *serviceLogFile != "" {
+			cfg.Log.File = *serviceLogFile
 		}
-
-		for _, u := range bytes.Split([]byte(rawURLs), []byte(",")) {
-			trimmed := string(bytes.TrimSpace(u))
-			if trimmed != "" {
-				urls = append(urls, trimmed)
-			}
-		}
-		timeout = 5 * time.Second
-		cooldownEnv := getEnv("ALERT_COOLDOWN_SECONDS", "60")
-		latencyEnv := getEnv("ALERT_LATENCY_THRESHOLD_MS", "0")
-		var cooldownSec int
-		var latencyMs int
-		fmt.Sscanf(cooldownEnv, "%d", &cooldownSec)
-		fmt.Sscanf(latencyEnv, "%d", &latencyMs)
-		if cooldownSec < 0 {
-			cooldownSec = 0
-		}
-		if latencyMs < 0 {
-			latencyMs = 0
-		}
-		policy = AlertPolicy{
-			Cooldown:         time.Duration(cooldownSec) * time.Second,
-			LatencyThreshold: time.Duration(latencyMs) * time.Millisecond,
+		setupLogger(cfg.Log.File)
+		fmt.Printf("已从 %s 加载配置\n", configPath)
+	} else {
+		cfg = configFromEnv()
+		if *serviceLogFile != "" {
+			cfg.Log.File = *serviceLogFile
 		}
+		setupLogger(cfg.Log.File)
 	}
 
-	if len(urls) == 0 {
-		fmt.Println("配置中的 URL 列表为空")
+	if len(cfg.Monitor.Targets) == 0 && len(cfg.Monitor.URLs) == 0 {
+		fmt.Println("配置中的目标列表为空")
 		os.Exit(1)
 	}
-
-	if webhook == "" {
-		fmt.Println("警告：未配置 FEISHU_WEBHOOK，将不会发送飞书告警，只会在控制台/日志中打印")
+	if cfg.Feishu.Webhook == "" && len(cfg.Alerting.Receivers) == 0 {
+		fmt.Println("警告：未配置飞书 webhook 或 alerting.receivers，将不会发送告警，只会在控制台/日志中打印")
 	}
 
-	runtime := MonitorRuntime{
-		URLs:     urls,
-		Webhook:  webhook,
-		Interval: time.Duration(intervalSec) * time.Second,
-		Timeout:  timeout,
-		Policy:   policy,
+	var reloader *ConfigReloader
+	if reloadable {
+		reloader, err = NewConfigReloader(configPath, cfg)
+	} else {
+		reloader, err = NewConfigReloader("", cfg)
+	}
+	if err != nil {
+		log.Fatalf("初始化监控 runtime 失败: %v", err)
 	}
 
 	run := func(ctx context.Context) {
-		startMetricsServer(ctx, ":2112")
-		runMonitorLoop(ctx, runtime)
+		startMetricsServer(ctx, ":2112", reloader)
+		if reloadable {
+			go reloader.WatchFile(ctx.Done())
+		}
+		go reloader.WatchSIGHUP(ctx.Done())
+		defer reloader.Close()
+		runMonitorLoop(ctx, reloader)
 	}
 
-	if handleWindowsService(run) {
+	if handleService(run) {
 		return
 	}
 
@@ -430,3 +313,47 @@ func main() {
 
 	run(ctx)
 }
+
+// configFromEnv 把环境变量拼装成一个 Config，使其可以走与 config.yaml 相同的
+// buildRuntime 路径；这个 Config 不对应任何文件，因此不会被热加载。
+func configFromEnv() *Config {
+	rawURLs := getEnv("MONITOR_URLS", "")
+	webhook := getEnv("FEISHU_WEBHOOK", "")
+	intervalStr := getEnv("INTERVAL_SECONDS", "10")
+	logFile := getEnv("LOG_FILE", "monitor.log")
+
+	if rawURLs == "" {
+		fmt.Println("请通过 config.yaml 或环境变量 MONITOR_URLS 设置要监控的 URL，多个用逗号分隔")
+		os.Exit(1)
+	}
+
+	var intervalSec int
+	fmt.Sscanf(intervalStr, "%d", &intervalSec)
+	if intervalSec <= 0 {
+		intervalSec = 10
+	}
+
+	var urls []string
+	for _, u := range bytes.Split([]byte(rawURLs), []byte(",")) {
+		trimmed := string(bytes.TrimSpace(u))
+		if trimmed != "" {
+			urls = append(urls, trimmed)
+		}
+	}
+
+	latencyEnv := getEnv("ALERT_LATENCY_THRESHOLD_MS", "0")
+	var latencyMs int
+	fmt.Sscanf(latencyEnv, "%d", &latencyMs)
+	if latencyMs < 0 {
+		latencyMs = 0
+	}
+
+	cfg := &Config{}
+	cfg.Monitor.URLs = urls
+	cfg.Monitor.Interval = intervalSec
+	cfg.Monitor.TimeoutSecond = 5
+	cfg.Feishu.Webhook = webhook
+	cfg.Log.File = logFile
+	cfg.Alert.LatencyThresholdMS = latencyMs
+	return cfg
+}
@@ -0,0 +1,111 @@
+package main
+
+// notifiers.go 提供 Dispatcher 可用的具体 Notifier 实现：飞书卡片与通用 webhook。
+// 两者都是把一个 AlertGroup 里的多条告警合并成一条消息发送，而不是逐条发送。
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// FeishuNotifier 把一个 AlertGroup 渲染成一张飞书互动卡片。
+type FeishuNotifier struct {
+	Webhook string
+}
+
+func (n *FeishuNotifier) Name() string { return "feishu" }
+
+func (n *FeishuNotifier) Notify(group *AlertGroup) error {
+	var lines string
+	for _, a := range group.Alerts {
+		lines += fmt.Sprintf(
+			"**URL**: %s\n**状态**: %s\n**详情**: %s\n\n",
+			a.Labels["url"], a.Labels["status"], a.Annotations["detail"],
+		)
+	}
+
+	card := map[string]interface{}{
+		"config": map[string]interface{}{
+			"wide_screen_mode": true,
+		},
+		"header": map[string]interface{}{
+			"title": map[string]string{
+				"tag":     "plain_text",
+				"content": fmt.Sprintf("HTTP 监控告警 (%d 条)", len(group.Alerts)),
+			},
+			"template": "red",
+		},
+		"elements": []interface{}{
+			map[string]interface{}{
+				"tag": "div",
+				"text": map[string]string{
+					"tag":     "lark_md",
+					"content": lines,
+				},
+			},
+		},
+	}
+
+	body := FeishuCard{
+		MsgType: "interactive",
+		Card:    card,
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.Post(n.Webhook, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("feishu 返回非 200 状态码: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// WebhookNotifier 把一个 AlertGroup 序列化为通用 JSON 结构 POST 给任意 webhook 接收端。
+type WebhookNotifier struct {
+	URL string
+}
+
+func (n *WebhookNotifier) Name() string { return "webhook" }
+
+type webhookPayload struct {
+	GroupLabels map[string]string     `json:"group_labels"`
+	Alerts      []webhookPayloadAlert `json:"alerts"`
+}
+
+type webhookPayloadAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+func (n *WebhookNotifier) Notify(group *AlertGroup) error {
+	payload := webhookPayload{GroupLabels: group.Labels}
+	for _, a := range group.Alerts {
+		payload.Alerts = append(payload.Alerts, webhookPayloadAlert{Labels: a.Labels, Annotations: a.Annotations})
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.Post(n.URL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook 返回非 2xx 状态码: %d", resp.StatusCode)
+	}
+	return nil
+}
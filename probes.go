@@ -0,0 +1,366 @@
+package main
+
+// probes.go 引入可插拔的探测类型，灵感来自 blackbox_exporter：Config.Monitor
+// 不再只接受裸 URL，而是接受带类型的 Target，每种类型对应一个 Prober 实现
+// （http/tcp/dns/icmp/tls）。monitorOnce 原来顺序请求 HTTP 的逻辑被
+// Scheduler 取代，Scheduler 并发调度所有目标的 Prober，probeTarget 为每个
+// 目标分别应用超时。
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+// TargetConfig 描述一个被监控的目标，Type 决定使用哪个 Prober 以及
+// 哪个子配置段（HTTP/TCP/DNS/ICMP/TLS）生效。
+type TargetConfig struct {
+	Name           string          `yaml:"name"`
+	Type           string          `yaml:"type"`
+	Address        string          `yaml:"address"`
+	TimeoutSecond  int             `yaml:"timeout_seconds"`
+	IntervalSecond int             `yaml:"interval_seconds"`
+	Schedule       string          `yaml:"schedule"`
+	HTTP           HTTPProbeConfig `yaml:"http"`
+	TCP            TCPProbeConfig  `yaml:"tcp"`
+	DNS            DNSProbeConfig  `yaml:"dns"`
+	ICMP           ICMPProbeConfig `yaml:"icmp"`
+	TLS            TLSProbeConfig  `yaml:"tls"`
+}
+
+func (t TargetConfig) timeout(def time.Duration) time.Duration {
+	if t.TimeoutSecond <= 0 {
+		return def
+	}
+	return time.Duration(t.TimeoutSecond) * time.Second
+}
+
+// label 是该目标在 Prometheus 指标和告警 labels 中使用的标识，沿用历史上的 "url" 含义。
+func (t TargetConfig) label() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Address
+}
+
+type HTTPProbeConfig struct {
+	Method         string            `yaml:"method"`
+	Headers        map[string]string `yaml:"headers"`
+	Body           string            `yaml:"body"`
+	ExpectStatus   string            `yaml:"expect_status_regex"`
+	ExpectBodyText string            `yaml:"expect_body_regex"`
+}
+
+type TCPProbeConfig struct {
+	Send   string `yaml:"send"`
+	Expect string `yaml:"expect"`
+}
+
+type DNSProbeConfig struct {
+	RecordType string `yaml:"record_type"` // A, AAAA, CNAME
+	Expect     string `yaml:"expect_regex"`
+}
+
+type ICMPProbeConfig struct {
+	Count int `yaml:"count"`
+}
+
+type TLSProbeConfig struct {
+	WarnDays int `yaml:"warn_days"`
+}
+
+// ProbeResult 是一次探测的统一结果，CertExpiry 只有 tls 探测器会填充。
+type ProbeResult struct {
+	Success    bool
+	Duration   time.Duration
+	Detail     string
+	CertExpiry time.Duration
+}
+
+// Prober 是一种探测方式的实现，Probe 必须遵守传入的 ctx 超时。
+type Prober interface {
+	Probe(ctx context.Context, target TargetConfig) ProbeResult
+}
+
+func proberFor(probeType string) (Prober, error) {
+	switch probeType {
+	case "", "http":
+		return HTTPProber{}, nil
+	case "tcp":
+		return TCPProber{}, nil
+	case "dns":
+		return DNSProber{}, nil
+	case "icmp":
+		return ICMPProber{}, nil
+	case "tls":
+		return TLSProber{}, nil
+	default:
+		return nil, fmt.Errorf("未知的探测类型: %s", probeType)
+	}
+}
+
+// HTTPProber 发起一个可配置方法/请求头/请求体的 HTTP 请求，并校验状态码与
+// 响应体是否满足正则表达式，取代旧版只检查 2xx 状态码的逻辑。
+type HTTPProber struct{}
+
+func (HTTPProber) Probe(ctx context.Context, target TargetConfig) ProbeResult {
+	start := time.Now()
+	method := target.HTTP.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var bodyReader *strings.Reader
+	if target.HTTP.Body != "" {
+		bodyReader = strings.NewReader(target.HTTP.Body)
+	}
+
+	var req *http.Request
+	var err error
+	if bodyReader != nil {
+		req, err = http.NewRequestWithContext(ctx, method, target.Address, bodyReader)
+	} else {
+		req, err = http.NewRequestWithContext(ctx, method, target.Address, nil)
+	}
+	if err != nil {
+		return ProbeResult{Success: false, Duration: time.Since(start), Detail: err.Error()}
+	}
+	for k, v := range target.HTTP.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	latency := time.Since(start)
+	if err != nil {
+		return ProbeResult{Success: false, Duration: latency, Detail: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	if target.HTTP.ExpectStatus != "" {
+		re, reErr := regexp.Compile(target.HTTP.ExpectStatus)
+		if reErr == nil && !re.MatchString(fmt.Sprintf("%d", resp.StatusCode)) {
+			return ProbeResult{Success: false, Duration: latency, Detail: fmt.Sprintf("状态码 %d 不匹配 %q", resp.StatusCode, target.HTTP.ExpectStatus)}
+		}
+	} else if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return ProbeResult{Success: false, Duration: latency, Detail: fmt.Sprintf("HTTP 状态码: %d", resp.StatusCode)}
+	}
+
+	if target.HTTP.ExpectBodyText != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if err != nil {
+			return ProbeResult{Success: false, Duration: latency, Detail: fmt.Sprintf("读取响应体失败: %v", err)}
+		}
+		re, reErr := regexp.Compile(target.HTTP.ExpectBodyText)
+		if reErr == nil && !re.Match(body) {
+			return ProbeResult{Success: false, Duration: latency, Detail: "响应体不匹配 expect_body_regex"}
+		}
+	}
+
+	return ProbeResult{Success: true, Duration: latency, Detail: fmt.Sprintf("HTTP %d, 耗时 %v", resp.StatusCode, latency)}
+}
+
+// TCPProber 建立一个 TCP 连接，可选发送一段数据并校验返回内容。
+type TCPProber struct{}
+
+func (TCPProber) Probe(ctx context.Context, target TargetConfig) ProbeResult {
+	start := time.Now()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", target.Address)
+	latency := time.Since(start)
+	if err != nil {
+		return ProbeResult{Success: false, Duration: latency, Detail: err.Error()}
+	}
+	defer conn.Close()
+
+	if target.TCP.Send != "" {
+		if _, err := conn.Write([]byte(target.TCP.Send)); err != nil {
+			return ProbeResult{Success: false, Duration: time.Since(start), Detail: fmt.Sprintf("发送数据失败: %v", err)}
+		}
+	}
+	if target.TCP.Expect != "" {
+		buf := make([]byte, 4096)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return ProbeResult{Success: false, Duration: time.Since(start), Detail: fmt.Sprintf("读取响应失败: %v", err)}
+		}
+		if !strings.Contains(string(buf[:n]), target.TCP.Expect) {
+			return ProbeResult{Success: false, Duration: time.Since(start), Detail: "响应内容不包含 expect 字符串"}
+		}
+	}
+
+	return ProbeResult{Success: true, Duration: time.Since(start), Detail: fmt.Sprintf("TCP 连接成功，耗时 %v", time.Since(start))}
+}
+
+// DNSProber 解析 A/AAAA/CNAME 记录并校验结果是否满足 expect_regex。
+type DNSProber struct{}
+
+func (DNSProber) Probe(ctx context.Context, target TargetConfig) ProbeResult {
+	start := time.Now()
+	resolver := net.DefaultResolver
+	recordType := target.DNS.RecordType
+	if recordType == "" {
+		recordType = "A"
+	}
+
+	var answers []string
+	var err error
+	switch strings.ToUpper(recordType) {
+	case "CNAME":
+		var cname string
+		cname, err = resolver.LookupCNAME(ctx, target.Address)
+		answers = []string{cname}
+	case "AAAA":
+		var ips []net.IP
+		ips, err = resolver.LookupIP(ctx, "ip6", target.Address)
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	default:
+		var ips []net.IP
+		ips, err = resolver.LookupIP(ctx, "ip4", target.Address)
+		for _, ip := range ips {
+			answers = append(answers, ip.String())
+		}
+	}
+	latency := time.Since(start)
+	if err != nil {
+		return ProbeResult{Success: false, Duration: latency, Detail: err.Error()}
+	}
+	if len(answers) == 0 {
+		return ProbeResult{Success: false, Duration: latency, Detail: "没有解析到任何记录"}
+	}
+
+	if target.DNS.Expect != "" {
+		re, reErr := regexp.Compile(target.DNS.Expect)
+		if reErr == nil {
+			matched := false
+			for _, a := range answers {
+				if re.MatchString(a) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return ProbeResult{Success: false, Duration: latency, Detail: fmt.Sprintf("解析结果 %v 不匹配 %q", answers, target.DNS.Expect)}
+			}
+		}
+	}
+
+	return ProbeResult{Success: true, Duration: latency, Detail: fmt.Sprintf("解析到 %v，耗时 %v", answers, latency)}
+}
+
+// ICMPProber 发送 ICMP echo 请求测量往返时延与丢包率。
+type ICMPProber struct{}
+
+func (ICMPProber) Probe(ctx context.Context, target TargetConfig) ProbeResult {
+	start := time.Now()
+	conn, err := icmp.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err != nil {
+		return ProbeResult{Success: false, Duration: time.Since(start), Detail: fmt.Sprintf("创建 ICMP 连接失败（可能需要 root 权限）: %v", err)}
+	}
+	defer conn.Close()
+
+	dst, err := net.ResolveIPAddr("ip4", target.Address)
+	if err != nil {
+		return ProbeResult{Success: false, Duration: time.Since(start), Detail: err.Error()}
+	}
+
+	count := target.ICMP.Count
+	if count <= 0 {
+		count = 1
+	}
+
+	sent, received := 0, 0
+	var lastRTT time.Duration
+	for i := 0; i < count; i++ {
+		msg := icmp.Message{
+			Type: ipv4.ICMPTypeEcho, Code: 0,
+			Body: &icmp.Echo{ID: os.Getpid() & 0xffff, Seq: i, Data: []byte("http-monitor")},
+		}
+		data, err := msg.Marshal(nil)
+		if err != nil {
+			continue
+		}
+		sendStart := time.Now()
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+		if _, err := conn.WriteTo(data, dst); err != nil {
+			sent++
+			continue
+		}
+		sent++
+
+		reply := make([]byte, 1500)
+		n, _, err := conn.ReadFrom(reply)
+		if err != nil {
+			continue
+		}
+		lastRTT = time.Since(sendStart)
+
+		parsed, err := icmp.ParseMessage(1, reply[:n])
+		if err == nil && parsed.Type == ipv4.ICMPTypeEchoReply {
+			received++
+		}
+	}
+
+	loss := float64(sent-received) / float64(sent) * 100
+	detail := fmt.Sprintf("发送 %d 收到 %d，丢包率 %.1f%%，最近一次 RTT %v", sent, received, loss, lastRTT)
+	return ProbeResult{Success: received > 0, Duration: time.Since(start), Detail: detail}
+}
+
+// TLSProber 建立 TLS 连接并返回证书剩余有效期，用于提前告警证书过期。
+type TLSProber struct{}
+
+func (TLSProber) Probe(ctx context.Context, target TargetConfig) ProbeResult {
+	start := time.Now()
+	var d net.Dialer
+	rawConn, err := d.DialContext(ctx, "tcp", target.Address)
+	if err != nil {
+		return ProbeResult{Success: false, Duration: time.Since(start), Detail: err.Error()}
+	}
+	defer rawConn.Close()
+
+	host, _, err := net.SplitHostPort(target.Address)
+	if err != nil {
+		host = target.Address
+	}
+
+	conn := tls.Client(rawConn, &tls.Config{ServerName: host})
+	defer conn.Close()
+	if err := conn.HandshakeContext(ctx); err != nil {
+		return ProbeResult{Success: false, Duration: time.Since(start), Detail: fmt.Sprintf("TLS 握手失败: %v", err)}
+	}
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return ProbeResult{Success: false, Duration: time.Since(start), Detail: "未获取到证书"}
+	}
+	expiry := time.Until(certs[0].NotAfter)
+
+	warnDays := target.TLS.WarnDays
+	if warnDays <= 0 {
+		warnDays = 14
+	}
+	if expiry < time.Duration(warnDays)*24*time.Hour {
+		return ProbeResult{
+			Success: false, Duration: time.Since(start), CertExpiry: expiry,
+			Detail: fmt.Sprintf("证书将在 %s 后过期（阈值 %d 天）", expiry.Round(time.Hour), warnDays),
+		}
+	}
+
+	return ProbeResult{
+		Success: true, Duration: time.Since(start), CertExpiry: expiry,
+		Detail: fmt.Sprintf("证书有效期剩余 %s", expiry.Round(time.Hour)),
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+// reload.go 实现配置热加载：监听 config.yaml 变化（fsnotify）和 SIGHUP 信号，
+// 重新解析、校验配置后原子地替换正在使用的 MonitorRuntime；校验失败时保留
+// 旧配置、只记录一条错误日志，不影响当前正在运行的监控——这是 Prometheus/
+// Alertmanager 处理热加载失败的一贯做法。
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	configReloadTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_monitor_config_reload_total",
+			Help: "Total number of configuration reload attempts, labeled by result",
+		},
+		[]string{"result"},
+	)
+	configLastReloadTimestamp = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "http_monitor_config_last_reload_timestamp_seconds",
+			Help: "Timestamp of the last successful configuration reload",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(configReloadTotal, configLastReloadTimestamp)
+}
+
+// ReloadConfig 对应 config.yaml 里的 reload: 小节，Token 用来保护 /-/reload 接口。
+type ReloadConfig struct {
+	Token string `yaml:"token"`
+}
+
+// ConfigReloader 持有当前生效的 MonitorRuntime，提供原子替换（热加载）能力。
+// configPath 为空时表示当前配置来自环境变量，不会有文件可以监听。
+type ConfigReloader struct {
+	configPath string
+	token      atomic.Pointer[string]
+
+	runtime atomic.Pointer[MonitorRuntime]
+
+	reloadMu        sync.Mutex
+	dispatcherStop  chan struct{}
+	remoteWriteStop chan struct{}
+}
+
+// NewConfigReloader 基于 cfg 构建初始 runtime，并启动它的 Dispatcher、Scheduler
+// 和（如果配置了 remote_write）RemoteWriteQueue。
+func NewConfigReloader(configPath string, cfg *Config) (*ConfigReloader, error) {
+	runtime, dispatcher, err := buildRuntime(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := make(chan struct{})
+	go dispatcher.Run(stop)
+	runtime.Scheduler.Start()
+
+	rwStop := make(chan struct{})
+	if runtime.RemoteWrite != nil {
+		go runtime.RemoteWrite.Run(rwStop)
+	}
+
+	r := &ConfigReloader{configPath: configPath, dispatcherStop: stop, remoteWriteStop: rwStop}
+	token := cfg.Reload.Token
+	r.token.Store(&token)
+	r.runtime.Store(runtime)
+	return r, nil
+}
+
+// Current 返回当前生效的 MonitorRuntime，调用方应当每次都重新获取，而不要缓存。
+func (r *ConfigReloader) Current() *MonitorRuntime {
+	return r.runtime.Load()
+}
+
+// Close 停止当前 runtime 里 Dispatcher、Scheduler 和 RemoteWriteQueue 的后台 goroutine。
+func (r *ConfigReloader) Close() {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+	close(r.dispatcherStop)
+	close(r.remoteWriteStop)
+	r.runtime.Load().Scheduler.Stop()
+}
+
+// Reload 重新读取、解析、校验配置文件，只有校验通过才会原子替换当前 runtime。
+func (r *ConfigReloader) Reload() error {
+	if r.configPath == "" {
+		return fmt.Errorf("当前配置来自环境变量，没有可重新加载的配置文件")
+	}
+
+	cfg, err := loadConfigFrom(r.configPath)
+	if err != nil {
+		configReloadTotal.WithLabelValues("failure").Inc()
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	runtime, dispatcher, err := buildRuntime(cfg)
+	if err != nil {
+		configReloadTotal.WithLabelValues("failure").Inc()
+		return fmt.Errorf("校验配置失败: %w", err)
+	}
+
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	newStop := make(chan struct{})
+	go dispatcher.Run(newStop)
+	runtime.Scheduler.Start()
+
+	newRWStop := make(chan struct{})
+	if runtime.RemoteWrite != nil {
+		go runtime.RemoteWrite.Run(newRWStop)
+	}
+
+	oldRuntime := r.runtime.Load()
+	oldStop := r.dispatcherStop
+	oldRWStop := r.remoteWriteStop
+	r.dispatcherStop = newStop
+	r.remoteWriteStop = newRWStop
+	close(oldStop)
+	close(oldRWStop)
+	oldRuntime.Scheduler.Stop()
+
+	token := cfg.Reload.Token
+	r.token.Store(&token)
+	r.runtime.Store(runtime)
+
+	configReloadTotal.WithLabelValues("success").Inc()
+	configLastReloadTimestamp.SetToCurrentTime()
+	log.Println("配置已热加载")
+	return nil
+}
+
+// WatchFile 监听 config.yaml 的写入事件并触发 Reload，stop 关闭时退出。
+//
+// 这里监听的是配置文件所在的目录，而不是文件本身：编辑器保存、k8s
+// ConfigMap 挂载更新配置都是通过原子 rename-over-symlink 替换文件完成的，
+// 这会删除文件原来的 inode，让针对该 inode 的 inotify watch 永久失效
+// （fsnotify 自己的文档里写明的限制）。只有监听父目录才能在文件被替换后
+// 继续收到后续事件，再按文件名过滤出我们关心的那一个。
+func (r *ConfigReloader) WatchFile(stop <-chan struct{}) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("创建 fsnotify watcher 失败: %v\n", err)
+		return
+	}
+	defer watcher.Close()
+
+	dir := filepath.Dir(r.configPath)
+	if err := watcher.Add(dir); err != nil {
+		log.Printf("监听配置目录 %s 失败: %v\n", dir, err)
+		return
+	}
+	name := filepath.Clean(r.configPath)
+
+	for {
+		select {
+		case <-stop:
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != name {
+				continue
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) != 0 {
+				if err := r.Reload(); err != nil {
+					log.Printf("配置热加载失败: %v\n", err)
+				}
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("fsnotify 错误: %v\n", err)
+		}
+	}
+}
+
+// WatchSIGHUP 注册 SIGHUP 处理，收到信号时触发 Reload，stop 关闭时退出。
+func (r *ConfigReloader) WatchSIGHUP(stop <-chan struct{}) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-sigCh:
+			log.Println("收到 SIGHUP，重新加载配置")
+			if err := r.Reload(); err != nil {
+				log.Printf("配置热加载失败: %v\n", err)
+			}
+		}
+	}
+}
+
+// ReloadHandler 返回 POST /-/reload 的处理函数，用 token 做简单鉴权，
+// 与 fsnotify/SIGHUP 共用同一个 Reload 实现。
+func (r *ConfigReloader) ReloadHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			http.Error(w, "只支持 POST", http.StatusMethodNotAllowed)
+			return
+		}
+		if token := r.token.Load(); token != nil && *token != "" && req.Header.Get("Authorization") != "Bearer "+*token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if err := r.Reload(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "reloaded")
+	}
+}
+
+// SilencesHandler 返回 /-/silences 的处理函数：GET 列出所有已保存的静默（包括已过期的），POST
+// 创建一条新的（复用与 /-/reload 相同的 token 鉴权）。这是 silences_file 唯一
+// 受支持的运行时写入入口，避免只能手工编辑 JSON 再整体 /-/reload 才能生效。
+func (r *ConfigReloader) SilencesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if token := r.token.Load(); token != nil && *token != "" && req.Header.Get("Authorization") != "Bearer "+*token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		silences := r.Current().Dispatcher.Silences()
+		if silences == nil {
+			http.Error(w, "未配置 alerting.silences_file", http.StatusNotFound)
+			return
+		}
+
+		switch req.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(silences.List())
+		case http.MethodPost:
+			var sil Silence
+			if err := json.NewDecoder(req.Body).Decode(&sil); err != nil {
+				http.Error(w, fmt.Sprintf("解析请求体失败: %v", err), http.StatusBadRequest)
+				return
+			}
+			if sil.StartsAt.IsZero() {
+				sil.StartsAt = time.Now()
+			}
+			if sil.EndsAt.IsZero() {
+				http.Error(w, "ends_at 不能为空", http.StatusBadRequest)
+				return
+			}
+			if err := silences.Add(sil); err != nil {
+				http.Error(w, fmt.Sprintf("保存静默失败: %v", err), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.Error(w, "只支持 GET/POST", http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+// remotewrite.go 实现一个可选的 remote_write sink：把每次探测产生的样本
+// 按 Prometheus remote write 协议推送到任意兼容端点（VictoriaMetrics、
+// Mimir 等），这样在无法被主动抓取 /metrics 的环境里也能上报数据。结构上
+// 参照 Prometheus 自身 remote storage 的队列/批量/退避思路，按这个项目的
+// 规模做了裁剪：一个有容量上限、满了就丢最老样本的内存队列，外加一个按
+// 数量或时间批量 flush 的后台 goroutine。
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+var (
+	remoteWriteQueueDepth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "http_monitor_remote_write_queue_depth",
+			Help: "Number of samples currently buffered waiting to be remote-written",
+		},
+	)
+	remoteWriteSendErrors = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "http_monitor_remote_write_send_errors_total",
+			Help: "Total number of failed remote_write send attempts",
+		},
+	)
+	remoteWriteDropped = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "http_monitor_remote_write_dropped_samples_total",
+			Help: "Total number of samples dropped because the remote_write queue was full",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(remoteWriteQueueDepth, remoteWriteSendErrors, remoteWriteDropped)
+}
+
+// RemoteWriteConfig 对应 config.yaml 里的 remote_write: 小节。
+type RemoteWriteConfig struct {
+	URL             string `yaml:"url"`
+	BasicAuthUser   string `yaml:"basic_auth_username"`
+	BasicAuthPass   string `yaml:"basic_auth_password"`
+	BearerToken     string `yaml:"bearer_token"`
+	MaxBatchSamples int    `yaml:"max_batch_samples"`
+	FlushIntervalS  int    `yaml:"flush_interval_seconds"`
+	QueueCapacity   int    `yaml:"queue_capacity"`
+}
+
+func (c RemoteWriteConfig) enabled() bool { return c.URL != "" }
+
+// Sample 是写入队列的一条时间序列样本，Name 对应 Prometheus 里的 __name__。
+type Sample struct {
+	Name      string
+	Labels    map[string]string
+	Value     float64
+	Timestamp time.Time
+}
+
+// RemoteWriteQueue 是一个有容量上限的内存队列，HTTPS + snappy 压缩的
+// protobuf WriteRequest 批量发送，5xx 响应按指数退避重试，队满时丢弃最老样本。
+type RemoteWriteQueue struct {
+	cfg    RemoteWriteConfig
+	client *http.Client
+
+	mu     sync.Mutex
+	buffer []Sample
+}
+
+func NewRemoteWriteQueue(cfg RemoteWriteConfig) *RemoteWriteQueue {
+	if cfg.MaxBatchSamples <= 0 {
+		cfg.MaxBatchSamples = 500
+	}
+	if cfg.FlushIntervalS <= 0 {
+		cfg.FlushIntervalS = 15
+	}
+	if cfg.QueueCapacity <= 0 {
+		cfg.QueueCapacity = 10000
+	}
+	return &RemoteWriteQueue{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Enqueue 把一条样本放入队列；队列满时丢弃最老的一条，保证内存占用有上限。
+func (q *RemoteWriteQueue) Enqueue(s Sample) {
+	q.mu.Lock()
+	if len(q.buffer) >= q.cfg.QueueCapacity {
+		q.buffer = q.buffer[1:]
+		remoteWriteDropped.Inc()
+	}
+	q.buffer = append(q.buffer, s)
+	depth := len(q.buffer)
+	q.mu.Unlock()
+	remoteWriteQueueDepth.Set(float64(depth))
+}
+
+// Run 按 flush_interval_seconds 定期把队列里的样本批量发送出去，stop 关闭时退出
+// 前会再 flush 一次，尽量不丢队列里剩下的数据。
+func (q *RemoteWriteQueue) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(time.Duration(q.cfg.FlushIntervalS) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			q.flush()
+			return
+		case <-ticker.C:
+			q.flush()
+		}
+	}
+}
+
+func (q *RemoteWriteQueue) flush() {
+	q.mu.Lock()
+	if len(q.buffer) == 0 {
+		q.mu.Unlock()
+		return
+	}
+	batch := q.buffer
+	q.buffer = nil
+	q.mu.Unlock()
+	remoteWriteQueueDepth.Set(0)
+
+	for len(batch) > 0 {
+		n := q.cfg.MaxBatchSamples
+		if n > len(batch) {
+			n = len(batch)
+		}
+		chunk := batch[:n]
+		batch = batch[n:]
+		if err := q.sendWithRetry(chunk); err != nil {
+			log.Printf("remote_write 发送失败: %v\n", err)
+		}
+	}
+}
+
+// permanentError 包装那些重试没有意义的失败（4xx：鉴权错误、payload 格式
+// 错误等），sendWithRetry 靠 errors.As 识别出它就立即放弃，不再继续退避重试。
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// sendWithRetry 对 5xx 响应和网络错误做指数退避重试；4xx 视为终态错误
+// （比如鉴权失败或 payload 格式错误），重试没有意义，直接放弃。
+func (q *RemoteWriteQueue) sendWithRetry(samples []Sample) error {
+	data, err := proto.Marshal(toWriteRequest(samples))
+	if err != nil {
+		return err
+	}
+	compressed := snappy.Encode(nil, data)
+
+	const maxAttempts = 5
+	backoff := time.Second
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		err := q.post(compressed)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		remoteWriteSendErrors.Inc()
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return fmt.Errorf("remote write 失败，不再重试: %w", perm.err)
+		}
+	}
+	return fmt.Errorf("尝试 %d 次后仍然失败: %w", maxAttempts, lastErr)
+}
+
+func (q *RemoteWriteQueue) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, q.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	if q.cfg.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+q.cfg.BearerToken)
+	} else if q.cfg.BasicAuthUser != "" {
+		req.SetBasicAuth(q.cfg.BasicAuthUser, q.cfg.BasicAuthPass)
+	}
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 5 {
+		return fmt.Errorf("remote write 端点返回 %d，可重试", resp.StatusCode)
+	}
+	if resp.StatusCode/100 == 4 {
+		return &permanentError{fmt.Errorf("remote write 端点返回 %d，判定为终态错误", resp.StatusCode)}
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("remote write 端点返回非 2xx 状态码: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func toWriteRequest(samples []Sample) *prompb.WriteRequest {
+	req := &prompb.WriteRequest{}
+	for _, s := range samples {
+		labels := make([]prompb.Label, 0, len(s.Labels)+1)
+		labels = append(labels, prompb.Label{Name: "__name__", Value: s.Name})
+		for k, v := range s.Labels {
+			labels = append(labels, prompb.Label{Name: k, Value: v})
+		}
+		req.Timeseries = append(req.Timeseries, prompb.TimeSeries{
+			Labels:  labels,
+			Samples: []prompb.Sample{{Value: s.Value, Timestamp: s.Timestamp.UnixMilli()}},
+		})
+	}
+	return req
+}
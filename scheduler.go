@@ -0,0 +1,219 @@
+package main
+
+// scheduler.go 把每个 Target 按自己的 interval_seconds 或 cron 风格 schedule
+// 独立调度（使用 robfig/cron/v3），取代了旧版所有目标共用一个 time.Ticker
+// 的做法。Scheduler 用一个有容量上限的 channel 充当 worker pool
+// （max_concurrent_probes），防止目标数很大时把文件描述符或出站连接耗尽；
+// 入队等待的时间记录为 http_monitor_probe_queue_seconds，调度时刻之后额外
+// 加上一个可配置比例的随机延迟，避免共用同一调度表达式的目标同时探测造成
+// 惊群。这个抖动只向后推迟，探测不会早于调度时刻发起。
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robfig/cron/v3"
+)
+
+var (
+	probeSuccess = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "probe_success",
+			Help: "Displays whether or not the probe was a success (1 for success, 0 for failure)",
+		},
+		[]string{"probe"},
+	)
+	probeDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "probe_duration_seconds",
+			Help:    "Returns how long the probe took to complete in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"probe"},
+	)
+	probeSSLExpiry = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "probe_ssl_earliest_cert_expiry",
+			Help: "Returns earliest SSL cert expiry date as seconds since epoch",
+		},
+		[]string{"probe"},
+	)
+	probeQueueSeconds = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "http_monitor_probe_queue_seconds",
+			Help:    "Time a scheduled probe spent waiting for a free worker slot",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(probeSuccess, probeDuration, probeSSLExpiry, probeQueueSeconds)
+}
+
+// Scheduler 拥有一个 cron.Cron 实例和一个用 buffered channel 实现的 worker
+// 池；每个 Target 注册一个 cron job，job 触发时先经过抖动再排队等待 worker。
+type Scheduler struct {
+	cron          *cron.Cron
+	workers       chan struct{}
+	jitterPercent int
+}
+
+// NewScheduler 创建一个 Scheduler，maxConcurrent <= 0 时使用一个保守的默认值。
+func NewScheduler(maxConcurrent int, jitterPercent int) *Scheduler {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 50
+	}
+	return &Scheduler{
+		cron:          cron.New(),
+		workers:       make(chan struct{}, maxConcurrent),
+		jitterPercent: jitterPercent,
+	}
+}
+
+// Schedule 为一个 Target 注册调度：优先使用 target.Schedule（cron 表达式），
+// 否则退化为 "@every <interval>"，interval 取 target 自己的 interval_seconds
+// 或者 defaultInterval。
+func (s *Scheduler) Schedule(target TargetConfig, defaultInterval time.Duration, defaultTimeout time.Duration, dispatcher *Dispatcher, policy AlertPolicy, rw *RemoteWriteQueue) error {
+	spec := target.Schedule
+	interval := defaultInterval
+	if target.IntervalSecond > 0 {
+		interval = time.Duration(target.IntervalSecond) * time.Second
+	}
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	if spec == "" {
+		spec = fmt.Sprintf("@every %s", interval)
+	}
+
+	sched, err := cron.ParseStandard(spec)
+	if err != nil {
+		return fmt.Errorf("目标 %s 的 schedule %q 无效: %w", target.label(), spec, err)
+	}
+	if resolved := resolvedInterval(sched); resolved > 0 {
+		interval = resolved
+	}
+
+	_, err = s.cron.AddFunc(spec, func() {
+		s.runOnce(target, defaultTimeout, dispatcher, policy, rw, interval)
+	})
+	if err != nil {
+		return fmt.Errorf("目标 %s 的 schedule %q 无效: %w", target.label(), spec, err)
+	}
+	return nil
+}
+
+// resolvedInterval 通过连续两次 Next() 推算出 schedule 的实际触发间隔，
+// 供 jitter 按真实调度周期（而不是固定的 1s）缩放。
+func resolvedInterval(sched cron.Schedule) time.Duration {
+	now := time.Now()
+	first := sched.Next(now)
+	second := sched.Next(first)
+	return second.Sub(first)
+}
+
+func (s *Scheduler) runOnce(target TargetConfig, defaultTimeout time.Duration, dispatcher *Dispatcher, policy AlertPolicy, rw *RemoteWriteQueue, interval time.Duration) {
+	if d := s.jitter(interval); d > 0 {
+		time.Sleep(d)
+	}
+
+	queuedAt := time.Now()
+	s.workers <- struct{}{}
+	probeQueueSeconds.Observe(time.Since(queuedAt).Seconds())
+	defer func() { <-s.workers }()
+
+	probeTarget(context.Background(), target, defaultTimeout, dispatcher, policy, rw)
+}
+
+// jitter 返回一个落在 [0, interval*jitterPercent%) 区间内的随机延迟，按目标
+// 实际解析出的调度间隔缩放，用来打散共用同一 schedule 的目标，避免它们同时
+// 发起探测。
+func (s *Scheduler) jitter(interval time.Duration) time.Duration {
+	if s.jitterPercent <= 0 || interval <= 0 {
+		return 0
+	}
+	maxJitter := interval * time.Duration(s.jitterPercent) / 100
+	if maxJitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(maxJitter)))
+}
+
+func (s *Scheduler) Start() { s.cron.Start() }
+
+func (s *Scheduler) Stop() {
+	<-s.cron.Stop().Done()
+}
+
+func probeTarget(ctx context.Context, target TargetConfig, defaultTimeout time.Duration, dispatcher *Dispatcher, policy AlertPolicy, rw *RemoteWriteQueue) {
+	prober, err := proberFor(target.Type)
+	if err != nil {
+		log.Printf("目标 %s 配置错误: %v\n", target.label(), err)
+		return
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, target.timeout(defaultTimeout))
+	defer cancel()
+
+	start := time.Now()
+	result := prober.Probe(probeCtx, target)
+
+	label := target.label()
+	probeDuration.WithLabelValues(label).Observe(result.Duration.Seconds())
+	if result.Success {
+		probeSuccess.WithLabelValues(label).Set(1)
+	} else {
+		probeSuccess.WithLabelValues(label).Set(0)
+	}
+	if result.CertExpiry > 0 {
+		probeSSLExpiry.WithLabelValues(label).Set(float64(time.Now().Add(result.CertExpiry).Unix()))
+	}
+
+	status := "OK"
+	alertReason := result.Detail
+	if !result.Success {
+		status = "ERROR"
+	} else if policy.LatencyThreshold > 0 && result.Duration > policy.LatencyThreshold {
+		status = "SLOW"
+		alertReason = fmt.Sprintf("探测耗时 %v 超过阈值 %v", result.Duration, policy.LatencyThreshold)
+	}
+
+	reqTotal.WithLabelValues(label, status).Inc()
+	reqDuration.WithLabelValues(label).Observe(result.Duration.Seconds())
+
+	if rw != nil {
+		now := time.Now()
+		successValue := 0.0
+		if result.Success {
+			successValue = 1
+		}
+		rw.Enqueue(Sample{Name: "probe_success", Labels: map[string]string{"probe": label}, Value: successValue, Timestamp: now})
+		rw.Enqueue(Sample{Name: "probe_duration_seconds", Labels: map[string]string{"probe": label}, Value: result.Duration.Seconds(), Timestamp: now})
+		if result.CertExpiry > 0 {
+			rw.Enqueue(Sample{Name: "probe_ssl_earliest_cert_expiry", Labels: map[string]string{"probe": label}, Value: float64(now.Add(result.CertExpiry).Unix()), Timestamp: now})
+		}
+	}
+
+	alert := Alert{
+		Labels:      map[string]string{"url": label, "status": status, "probe": target.Type},
+		Annotations: map[string]string{"detail": result.Detail, "reason": alertReason},
+		StartsAt:    start,
+	}
+	if status == "OK" {
+		alert.EndsAt = time.Now()
+	}
+	if dispatcher != nil {
+		dispatcher.Send(alert)
+	}
+
+	if status != "OK" {
+		log.Printf("[ALERT] %s - %s (reason: %s)\n", label, result.Detail, alertReason)
+	} else {
+		log.Printf("[OK] %s - %s\n", label, result.Detail)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+// service.go 把原来只在 Windows 下编译的 kardianos/service 接入逻辑搬到了
+// 平台无关的文件里：kardianos/service 本身在 Linux 上生成 systemd unit、在
+// macOS 上生成 launchd plist、在 Windows 上注册 Windows 服务，-service
+// install|uninstall|start|stop|status|run 这套子命令在三个平台上行为一致。
+// 真正的平台特定逻辑极少，被收敛到 service_windows.go 里的
+// platformServiceOptions。
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/kardianos/service"
+)
+
+var (
+	serviceCommand    = flag.String("service", "", "控制系统服务: install|uninstall|start|stop|status|run")
+	serviceConfigPath = flag.String("config", "config.yaml", "服务运行/安装时使用的配置文件路径")
+	serviceLogFile    = flag.String("log-file", "", "服务运行/安装时使用的日志文件路径，留空则使用配置文件里的设置")
+)
+
+// platformServiceOptions 携带平台特有的 service.Config.Option，默认为空；
+// Windows 在 service_windows.go 里覆盖它。
+var platformServiceOptions = service.KeyValue{}
+
+type monitorProgram struct {
+	run    func(context.Context)
+	cancel context.CancelFunc
+}
+
+func (p *monitorProgram) Start(s service.Service) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	go p.run(ctx)
+	return nil
+}
+
+func (p *monitorProgram) Stop(s service.Service) error {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	time.Sleep(2 * time.Second)
+	return nil
+}
+
+// buildServiceConfig 组装 kardianos/service 的配置。Arguments 把 --config
+// 和 --log-file 固化进生成的 systemd unit / launchd plist / Windows 服务里，
+// 这样被服务管理器启动时（工作目录通常不是仓库目录）也能找到正确的文件。
+func buildServiceConfig() *service.Config {
+	args := []string{"-service", "run"}
+	if *serviceConfigPath != "" {
+		configPath := *serviceConfigPath
+		if abs, err := filepath.Abs(configPath); err == nil {
+			configPath = abs
+		}
+		args = append(args, "--config", configPath)
+	}
+	if *serviceLogFile != "" {
+		logFile := *serviceLogFile
+		if abs, err := filepath.Abs(logFile); err == nil {
+			logFile = abs
+		}
+		args = append(args, "--log-file", logFile)
+	}
+
+	return &service.Config{
+		Name:        "HttpMonitor",
+		DisplayName: "HTTP Monitor",
+		Description: "Monitors HTTP endpoints and sends Feishu alerts.",
+		Arguments:   args,
+		Option:      platformServiceOptions,
+	}
+}
+
+// handleService 是 -service 子命令的平台无关入口：install/uninstall/start/
+// stop/status 都只是操作系统服务管理器，run 才会真正执行 runFn。
+func handleService(runFn func(context.Context)) bool {
+	prg := &monitorProgram{run: runFn}
+	s, err := service.New(prg, buildServiceConfig())
+	if err != nil {
+		log.Fatalf("创建系统服务失败: %v", err)
+	}
+
+	if serviceCommand != nil && *serviceCommand != "" {
+		switch *serviceCommand {
+		case "install", "uninstall", "start", "stop":
+			if err := service.Control(s, *serviceCommand); err != nil {
+				log.Fatalf("执行服务命令 %s 失败: %v", *serviceCommand, err)
+			}
+			log.Printf("服务命令 %s 执行成功\n", *serviceCommand)
+			return true
+		case "status":
+			fmt.Println(describeServiceStatus(s))
+			return true
+		case "run":
+			if err := s.Run(); err != nil {
+				log.Fatalf("以服务模式运行失败: %v", err)
+			}
+			return true
+		default:
+			log.Fatalf("未知 service 命令: %s", *serviceCommand)
+		}
+	}
+
+	// 没有 -service 参数：
+	// - 如果在交互模式（控制台运行），走普通 main 流程
+	// - 如果被服务管理器启动（非交互），必须走 s.Run()
+	if !service.Interactive() {
+		if err := s.Run(); err != nil {
+			log.Fatalf("作为系统服务运行失败: %v", err)
+		}
+		return true
+	}
+
+	return false
+}
+
+func describeServiceStatus(s service.Service) string {
+	status, err := s.Status()
+	if err != nil {
+		return fmt.Sprintf("未安装或无法查询状态: %v", err)
+	}
+	switch status {
+	case service.StatusRunning:
+		return "running"
+	case service.StatusStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}